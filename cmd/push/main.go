@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,10 +13,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/spf13/cobra"
 	"helm-push-artifactory-plugin/pkg/artifactory"
 	"helm-push-artifactory-plugin/pkg/helm"
+	"helm-push-artifactory-plugin/pkg/oci"
 	helmrepo "helm-push-artifactory-plugin/pkg/repo"
 	"helm-push-artifactory-plugin/pkg/version"
 )
@@ -36,6 +41,19 @@ type (
 		insecureSkipVerify bool
 		skipReindex        bool
 		overrides          []string
+		ociMode            bool
+		sign               bool
+		key                string
+		keyring            string
+		passphraseFile     string
+		dependencyUpdate   bool
+		properties         []string
+		buildName          string
+		buildNumber        string
+		buildURL           string
+		recursive          bool
+		parallelism        int
+		keepGoing          bool
 	}
 )
 
@@ -49,6 +67,9 @@ Examples:
   $ helm push-artifactory . https://artifactory/repo                       # package and push chart directory
   $ helm push-artifactory . --version="7c4d121" https://artifactory/repo   # override version in Chart.yaml
   $ helm push-artifactory mychart-0.1.0.tgz my-helm-repo                   # push mychart-0.1.0.tgz to a "my-helm-repo" repository
+  $ helm push-artifactory . oci://artifactory/helm-oci-repo                # package and push chart as an OCI artifact
+  $ helm push-artifactory . https://artifactory/repo --property git.commit=$GIT_COMMIT --build-name ci --build-number 42
+  $ helm push-artifactory ./charts/ https://artifactory/repo --recursive --parallelism 8 --keep-going
 `
 )
 
@@ -88,6 +109,19 @@ func newPushCmd(args []string) (*cobra.Command, error) {
 	f.StringVarP(&p.keyFile, "key-file", "", "", "Identify HTTPS client using this SSL key file [$HELM_REPO_KEY_FILE]")
 	f.BoolVarP(&p.insecureSkipVerify, "insecure", "", false, "Connect to server with an insecure way by skipping certificate verification [$HELM_REPO_INSECURE]")
 	f.BoolVarP(&p.skipReindex, "skip-reindex", "", false, "Avoid trigger reindex in the repository after pushing the chart [$HELM_REPO_SKIP_REINDEX]")
+	f.BoolVarP(&p.ociMode, "oci", "", false, "Push the chart as an OCI artifact instead of through the Artifactory REST API [$HELM_REPO_OCI]")
+	f.BoolVarP(&p.sign, "sign", "", false, "Sign the chart package and push the provenance file [$HELM_REPO_SIGN]")
+	f.StringVarP(&p.key, "key", "", "", "Name of the PGP key to sign with [$HELM_REPO_SIGN_KEY]")
+	f.StringVarP(&p.keyring, "keyring", "", "", "Path to the PGP keyring containing the signing key [$HELM_REPO_SIGN_KEYRING]")
+	f.StringVarP(&p.passphraseFile, "passphrase-file", "", "", "Path to a file containing the passphrase for the signing key [$HELM_REPO_SIGN_PASSPHRASE_FILE]")
+	f.BoolVarP(&p.dependencyUpdate, "dependency-update", "d", false, "Run helm dependency update before packaging a chart directory [$HELM_REPO_DEPENDENCY_UPDATE]")
+	f.StringArrayVarP(&p.properties, "property", "", []string{}, "<key>=<value> pairs, set as Artifactory properties on the uploaded chart (can be repeated) [$HELM_REPO_PROPERTIES]")
+	f.StringVarP(&p.buildName, "build-name", "", "", "CI build name to record this upload against [$HELM_REPO_BUILD_NAME]")
+	f.StringVarP(&p.buildNumber, "build-number", "", "", "CI build number to record this upload against [$HELM_REPO_BUILD_NUMBER]")
+	f.StringVarP(&p.buildURL, "build-url", "", "", "CI build URL to record in the build info [$HELM_REPO_BUILD_URL]")
+	f.BoolVarP(&p.recursive, "recursive", "r", false, "Treat the chart argument as a directory tree and push every chart found under it")
+	f.IntVarP(&p.parallelism, "parallelism", "", 4, "Number of charts to push concurrently in --recursive mode")
+	f.BoolVarP(&p.keepGoing, "keep-going", "", false, "In --recursive mode, push every chart even if some fail, instead of stopping at the first failure")
 	f.Parse(args)
 	return cmd, nil
 }
@@ -123,15 +157,83 @@ func (p *pushCmd) setFieldsFromEnv() {
 	if v, ok := os.LookupEnv("HELM_REPO_SKIP_REINDEX"); ok {
 		p.skipReindex, _ = strconv.ParseBool(v)
 	}
+	if v, ok := os.LookupEnv("HELM_REPO_OCI"); ok {
+		p.ociMode, _ = strconv.ParseBool(v)
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_SIGN"); ok {
+		p.sign, _ = strconv.ParseBool(v)
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_SIGN_KEY"); ok && p.key == "" {
+		p.key = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_SIGN_KEYRING"); ok && p.keyring == "" {
+		p.keyring = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_SIGN_PASSPHRASE_FILE"); ok && p.passphraseFile == "" {
+		p.passphraseFile = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_DEPENDENCY_UPDATE"); ok {
+		p.dependencyUpdate, _ = strconv.ParseBool(v)
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_PROPERTIES"); ok && len(p.properties) == 0 {
+		p.properties = strings.Split(v, ",")
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_BUILD_NAME"); ok && p.buildName == "" {
+		p.buildName = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_BUILD_NUMBER"); ok && p.buildNumber == "" {
+		p.buildNumber = v
+	}
+	if v, ok := os.LookupEnv("HELM_REPO_BUILD_URL"); ok && p.buildURL == "" {
+		p.buildURL = v
+	}
+}
+
+// parseProperties turns "key=value" pairs (as accepted by --property) into
+// a map, for use as Artifactory matrix params.
+func parseProperties(pairs []string) (map[string]string, error) {
+	properties := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --property %q, expected key=value", pair)
+		}
+		properties[parts[0]] = parts[1]
+	}
+	return properties, nil
 }
 
 func (p *pushCmd) push() error {
+	if err := p.resolveRepository(); err != nil {
+		return err
+	}
+
+	ociTarget := p.ociMode || strings.HasPrefix(p.repository, "oci://")
+
+	if p.sign && ociTarget {
+		return errors.New("--sign is not supported with --oci: provenance files have no OCI Distribution Spec equivalent yet")
+	}
+
+	if ociTarget && (len(p.properties) > 0 || p.buildName != "" || p.buildNumber != "" || p.buildURL != "") {
+		return errors.New("--property/--build-name/--build-number/--build-url are not supported with --oci: Artifactory properties and Build Info are REST-API-only concepts with no OCI Distribution Spec equivalent")
+	}
+
+	if p.recursive {
+		return p.pushRecursive()
+	}
+	return p.pushOne(p.chartName, true)
+}
+
+// resolveRepository turns p.repository into a concrete URL, filling in
+// username/password/TLS settings from the matching local repo entry when
+// p.repository names one rather than being a URL itself.
+func (p *pushCmd) resolveRepository() error {
 	var repo *helmrepo.Repo
 	var err error
 
 	// If the argument looks like a URL, just create a temp repo object
 	// instead of looking for the entry in the local repository list
-	if regexp.MustCompile(`^https?://`).MatchString(p.repository) {
+	if regexp.MustCompile(`^(https?|oci)://`).MatchString(p.repository) {
 		// Check valid URL
 		_, err = url.ParseRequestURI(p.repository)
 	} else {
@@ -142,28 +244,6 @@ func (p *pushCmd) push() error {
 		return err
 	}
 
-	chart, err := helm.GetChartByName(p.chartName)
-	if err != nil {
-		return err
-	}
-
-	// version override
-	if p.chartVersion != "" {
-		chart.SetVersion(p.chartVersion)
-	}
-
-	// app version override
-	if p.appVersion != "" {
-		chart.SetAppVersion(p.appVersion)
-	}
-
-	if len(p.overrides) > 0 {
-		err := chart.OverrideValues(p.overrides)
-		if err != nil {
-			return err
-		}
-	}
-
 	if repo != nil {
 		p.repository = repo.URL
 		if p.username == "" {
@@ -182,6 +262,75 @@ func (p *pushCmd) push() error {
 			p.keyFile = repo.KeyFile
 		}
 	}
+	return nil
+}
+
+// pushRecursive discovers every chart under p.chartName and pushes them
+// concurrently (bounded by p.parallelism), triggering a single reindex
+// once all uploads have succeeded.
+func (p *pushCmd) pushRecursive() error {
+	if p.chartVersion != "" || p.appVersion != "" || len(p.overrides) > 0 {
+		return errors.New("--recursive cannot be combined with --version, --app-version or --set: each discovered chart would be stamped with the same value")
+	}
+
+	charts, err := helm.DiscoverCharts(p.chartName)
+	if err != nil {
+		return err
+	}
+	if len(charts) == 0 {
+		return fmt.Errorf("no charts found under %q", p.chartName)
+	}
+
+	parallelism := p.parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	sem := make(chan struct{}, parallelism)
+	results := make([]error, len(charts))
+	var aborted int32
+	var wg sync.WaitGroup
+
+	for i, chartPath := range charts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chartPath string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if !p.keepGoing && atomic.LoadInt32(&aborted) != 0 {
+				results[i] = fmt.Errorf("skipped: a prior chart failed and --keep-going was not set")
+				fmt.Printf("%-50s SKIPPED\n", chartPath)
+				return
+			}
+
+			err := p.pushOne(chartPath, false)
+			results[i] = err
+			if err != nil {
+				fmt.Printf("%-50s FAILED: %v\n", chartPath, err)
+				if !p.keepGoing {
+					atomic.StoreInt32(&aborted, 1)
+				}
+				return
+			}
+			fmt.Printf("%-50s OK\n", chartPath)
+		}(i, chartPath)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range results {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", charts[i], err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d charts failed to push:\n%s", len(failed), len(charts), strings.Join(failed, "\n"))
+	}
+
+	if p.skipReindex || p.ociMode || strings.HasPrefix(p.repository, "oci://") {
+		return nil
+	}
 
 	client, err := artifactory.NewClient(
 		artifactory.URL(p.repository),
@@ -195,10 +344,59 @@ func (p *pushCmd) push() error {
 		artifactory.KeyFile(p.keyFile),
 		artifactory.InsecureSkipVerify(p.insecureSkipVerify),
 	)
+	if err != nil {
+		return err
+	}
 
+	resp, err := client.ReindexArtifactoryRepo()
 	if err != nil {
 		return err
 	}
+	return handleReindexResponse(resp)
+}
+
+// pushOne packages and uploads a single chart (a chart directory or a
+// packaged .tgz on disk), optionally reindexing the repository afterwards.
+func (p *pushCmd) pushOne(chartName string, reindex bool) error {
+	if p.dependencyUpdate {
+		if fi, statErr := os.Stat(chartName); statErr == nil && fi.IsDir() {
+			err := helm.UpdateDependencies(chartName, helm.DependencyResolverOptions{
+				Username:           p.username,
+				Password:           p.password,
+				AccessToken:        p.accessToken,
+				ApiKey:             p.apiKey,
+				CAFile:             p.caFile,
+				CertFile:           p.certFile,
+				KeyFile:            p.keyFile,
+				InsecureSkipVerify: p.insecureSkipVerify,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	chart, err := helm.GetChartByName(chartName)
+	if err != nil {
+		return err
+	}
+
+	// version override
+	if p.chartVersion != "" {
+		chart.SetVersion(p.chartVersion)
+	}
+
+	// app version override
+	if p.appVersion != "" {
+		chart.SetAppVersion(p.appVersion)
+	}
+
+	if len(p.overrides) > 0 {
+		err := chart.OverrideValues(p.overrides)
+		if err != nil {
+			return err
+		}
+	}
 
 	tmp, err := ioutil.TempDir("", "helm-push-artifactory-")
 	if err != nil {
@@ -211,7 +409,43 @@ func (p *pushCmd) push() error {
 		return err
 	}
 
-	resp, err := client.UploadChartPackage(chart.Metadata.Name, chartPackagePath)
+	var provPackagePath string
+	if p.sign {
+		provPackagePath, err = chart.Sign(chartPackagePath, p.key, p.keyring, p.passphraseFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.ociMode || strings.HasPrefix(p.repository, "oci://") {
+		return p.pushOCI(chart, chartPackagePath)
+	}
+
+	properties, err := parseProperties(p.properties)
+	if err != nil {
+		return err
+	}
+
+	client, err := artifactory.NewClient(
+		artifactory.URL(p.repository),
+		artifactory.Path(p.path),
+		artifactory.Username(p.username),
+		artifactory.Password(p.password),
+		artifactory.AccessToken(p.accessToken),
+		artifactory.ApiKey(p.apiKey),
+		artifactory.CAFile(p.caFile),
+		artifactory.CertFile(p.certFile),
+		artifactory.KeyFile(p.keyFile),
+		artifactory.InsecureSkipVerify(p.insecureSkipVerify),
+		artifactory.Properties(properties),
+		artifactory.BuildInfo(p.buildName, p.buildNumber, p.buildURL),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.UploadChartPackage(chart.Metadata.Name, chart.Metadata.Version, chartPackagePath)
 	if err != nil {
 		return err
 	}
@@ -220,7 +454,23 @@ func (p *pushCmd) push() error {
 		return err
 	}
 
-	if p.skipReindex {
+	if p.sign {
+		tarball, err := ioutil.ReadFile(chartPackagePath)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(tarball)
+
+		resp, err = client.UploadProvenance(provPackagePath, hex.EncodeToString(sum[:]))
+		if err != nil {
+			return err
+		}
+		if err = handlePushResponse(resp); err != nil {
+			return err
+		}
+	}
+
+	if !reindex || p.skipReindex {
 		return nil
 	}
 
@@ -231,6 +481,81 @@ func (p *pushCmd) push() error {
 	return handleReindexResponse(resp)
 }
 
+// pushOCI pushes the packaged chart to an OCI Distribution Spec endpoint
+// instead of the Artifactory REST API. Artifactory indexes OCI tags
+// automatically, so no reindex call is made afterwards.
+func (p *pushCmd) pushOCI(chart *helm.Chart, chartPackagePath string) error {
+	registry, repoName, err := splitOCIRepository(p.repository)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := artifactory.NewTLSConfig(p.caFile, p.certFile, p.keyFile, p.insecureSkipVerify)
+	if err != nil {
+		return err
+	}
+
+	client, err := oci.NewClient(registry,
+		oci.Username(p.username),
+		oci.Password(p.password),
+		oci.AccessToken(p.accessToken),
+		oci.ApiKey(p.apiKey),
+		oci.TLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Login(repoName); err != nil {
+		return err
+	}
+
+	configBytes, err := json.Marshal(chart.Metadata)
+	if err != nil {
+		return err
+	}
+	configDesc, err := client.PushBlob(repoName, configBytes, oci.ConfigMediaType)
+	if err != nil {
+		return err
+	}
+
+	layerBytes, err := ioutil.ReadFile(chartPackagePath)
+	if err != nil {
+		return err
+	}
+	layerDesc, err := client.PushBlob(repoName, layerBytes, oci.ChartLayerMediaType)
+	if err != nil {
+		return err
+	}
+
+	manifest := oci.BuildManifest(configDesc, layerDesc)
+	if err := client.PushManifest(repoName, chart.Metadata.Version, manifest); err != nil {
+		return err
+	}
+
+	fmt.Println("Done.")
+	return nil
+}
+
+// splitOCIRepository splits an "oci://host/repo" or plain "host/repo"
+// target (as used with --oci against a non-oci:// URL) into the registry
+// host and the repository name the distribution API expects.
+func splitOCIRepository(target string) (registry, repoName string, err error) {
+	if !regexp.MustCompile(`^https?://`).MatchString(target) {
+		target = "https://" + strings.TrimPrefix(target, "oci://")
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid OCI repository %q: %v", target, err)
+	}
+	repoName = strings.Trim(u.Path, "/")
+	if repoName == "" {
+		return "", "", fmt.Errorf("OCI repository %q is missing a repository name", target)
+	}
+	return u.Scheme + "://" + u.Host, repoName, nil
+}
+
 func handleReindexResponse(resp *http.Response) error {
 	defer resp.Body.Close()
 	b, err := ioutil.ReadAll(resp.Body)
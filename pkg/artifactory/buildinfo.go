@@ -0,0 +1,105 @@
+package artifactory
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+type buildInfoArtifact struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Sha1   string `json:"sha1"`
+	Sha256 string `json:"sha256"`
+	Md5    string `json:"md5"`
+}
+
+type buildInfoModule struct {
+	ID        string              `json:"id"`
+	Artifacts []buildInfoArtifact `json:"artifacts"`
+}
+
+type buildInfo struct {
+	Name    string             `json:"name"`
+	Number  string             `json:"number"`
+	URL     string             `json:"url,omitempty"`
+	Modules []buildInfoModule  `json:"modules"`
+}
+
+// publishBuildInfo PUTs a Build Info document to /api/build describing
+// the module (the chart name/version) and a single artifact entry with
+// the tgz's SHA1/SHA256/MD5, so CI systems can trace the upload back to a
+// build.
+func (c *Client) publishBuildInfo(chartName, chartVersion, chartPackagePath string) error {
+	data, err := ioutil.ReadFile(chartPackagePath)
+	if err != nil {
+		return err
+	}
+	sha1sum := sha1.Sum(data)
+	sha256sum := sha256.Sum256(data)
+	md5sum := md5.Sum(data)
+
+	info := buildInfo{
+		Name:   c.buildName,
+		Number: c.buildNumber,
+		URL:    c.buildURL,
+		Modules: []buildInfoModule{
+			{
+				ID: fmt.Sprintf("%s:%s", chartName, chartVersion),
+				Artifacts: []buildInfoArtifact{
+					{
+						Type:   "tgz",
+						Name:   path.Base(chartPackagePath),
+						Sha1:   hex.EncodeToString(sha1sum[:]),
+						Sha256: hex.EncodeToString(sha256sum[:]),
+						Md5:    hex.EncodeToString(md5sum[:]),
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return err
+	}
+	// Build Info is an instance-wide API, not scoped to the target
+	// repository, so it lives under the instance's context root (e.g.
+	// "/artifactory") rather than under the repository key c.url ends in.
+	trimmed := strings.TrimSuffix(u.Path, "/")
+	contextRoot := strings.TrimSuffix(trimmed, "/"+path.Base(trimmed))
+	u.Path = contextRoot + "/api/build"
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authenticate(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package artifactory
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Client talks to an Artifactory Helm repository over its REST API.
+type Client struct {
+	url         string
+	path        string
+	username    string
+	password    string
+	accessToken string
+	apiKey      string
+	certFile    string
+	keyFile     string
+	properties  map[string]string
+	buildName   string
+	buildNumber string
+	buildURL    string
+	httpClient  *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client) error
+
+// NewClient builds an Artifactory client from the given options.
+func NewClient(opts ...Option) (*Client, error) {
+	c := &Client{
+		httpClient: &http.Client{},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.url == "" {
+		return nil, fmt.Errorf("artifactory repository URL is required")
+	}
+	if c.certFile != "" && c.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %v", err)
+		}
+		tlsConfig(c).Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// URL sets the repository URL, e.g. https://artifactory.example.com/artifactory/helm-local.
+func URL(u string) Option {
+	return func(c *Client) error {
+		c.url = strings.TrimSuffix(u, "/")
+		return nil
+	}
+}
+
+// Path sets an optional sub-path under the repository to push charts to.
+func Path(p string) Option {
+	return func(c *Client) error {
+		c.path = strings.Trim(p, "/")
+		return nil
+	}
+}
+
+// Username sets the HTTP basic auth username.
+func Username(u string) Option {
+	return func(c *Client) error {
+		c.username = u
+		return nil
+	}
+}
+
+// Password sets the HTTP basic auth password.
+func Password(p string) Option {
+	return func(c *Client) error {
+		c.password = p
+		return nil
+	}
+}
+
+// AccessToken sets a bearer token sent in the Authorization header.
+func AccessToken(t string) Option {
+	return func(c *Client) error {
+		c.accessToken = t
+		return nil
+	}
+}
+
+// ApiKey sets the value of the X-JFrog-Art-Api header.
+func ApiKey(k string) Option {
+	return func(c *Client) error {
+		c.apiKey = k
+		return nil
+	}
+}
+
+// CAFile configures the client to trust certificates signed by the CA
+// bundle at the given path.
+func CAFile(f string) Option {
+	return func(c *Client) error {
+		if f == "" {
+			return nil
+		}
+		pem, err := ioutil.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("unable to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("unable to parse CA file %q", f)
+		}
+		tlsConfig(c).RootCAs = pool
+		return nil
+	}
+}
+
+// CertFile configures the client to present the given client certificate.
+// Must be paired with KeyFile.
+func CertFile(f string) Option {
+	return func(c *Client) error {
+		c.certFile = f
+		return nil
+	}
+}
+
+// KeyFile configures the client to present the given client key. Must be
+// paired with CertFile.
+func KeyFile(f string) Option {
+	return func(c *Client) error {
+		c.keyFile = f
+		return nil
+	}
+}
+
+// InsecureSkipVerify disables TLS certificate verification.
+func InsecureSkipVerify(insecure bool) Option {
+	return func(c *Client) error {
+		tlsConfig(c).InsecureSkipVerify = insecure
+		return nil
+	}
+}
+
+// Properties sets Artifactory matrix params to apply to the upload, so
+// they're set atomically with it (e.g. for CI traceability metadata).
+func Properties(properties map[string]string) Option {
+	return func(c *Client) error {
+		c.properties = properties
+		return nil
+	}
+}
+
+// BuildInfo configures the CI build this upload should be recorded
+// against. When name is non-empty, a Build Info document is PUT to
+// /api/build after a successful upload.
+func BuildInfo(name, number, url string) Option {
+	return func(c *Client) error {
+		c.buildName = name
+		c.buildNumber = number
+		c.buildURL = url
+		return nil
+	}
+}
+
+func tlsConfig(c *Client) *tls.Config {
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = &http.Transport{TLSClientConfig: &tls.Config{}}
+		c.httpClient.Transport = transport
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	return transport.TLSClientConfig
+}
+
+func (c *Client) chartURL(filename string) string {
+	p := c.url
+	if c.path != "" {
+		p = p + "/" + c.path
+	}
+	return p + "/" + filename + matrixParams(c.properties)
+}
+
+// matrixParams renders Artifactory matrix params (";key=value;key=value"),
+// URL-escaping each key and value so they can be set atomically with an
+// upload by appending them to the request path.
+func matrixParams(properties map[string]string) string {
+	if len(properties) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(";")
+		b.WriteString(url.QueryEscape(k))
+		b.WriteString("=")
+		b.WriteString(url.QueryEscape(properties[k]))
+	}
+	return b.String()
+}
+
+func (c *Client) authenticate(req *http.Request) {
+	switch {
+	case c.accessToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	case c.apiKey != "":
+		req.Header.Set("X-JFrog-Art-Api", c.apiKey)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+}
+
+// UploadChartPackage PUTs the packaged chart tarball to the repository. On
+// success, if build info was configured via the BuildInfo option, it also
+// publishes a Build Info document describing the upload; a failure to do
+// so is only logged, since not every Artifactory edition enables it.
+func (c *Client) UploadChartPackage(chartName, chartVersion, chartPackagePath string) (*http.Response, error) {
+	resp, err := c.uploadFile(chartPackagePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusCreated && c.buildName != "" {
+		if err := c.publishBuildInfo(chartName, chartVersion, chartPackagePath); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: unable to publish build info: %v\n", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// UploadProvenance PUTs a chart's .prov provenance file next to its
+// tarball, setting the Artifactory checksum header so the upload is
+// verified against the digest the file was signed with.
+func (c *Client) UploadProvenance(provPackagePath, sha256sum string) (*http.Response, error) {
+	return c.uploadFile(provPackagePath, map[string]string{
+		"X-Checksum-Sha256": sha256sum,
+	})
+}
+
+func (c *Client) uploadFile(filePath string, headers map[string]string) (*http.Response, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	req, err := http.NewRequest(http.MethodPut, c.chartURL(path.Base(filePath)), f)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	c.authenticate(req)
+	return c.httpClient.Do(req)
+}
+
+// ReindexArtifactoryRepo triggers a Helm repository reindex so the
+// uploaded chart shows up in index.yaml.
+func (c *Client) ReindexArtifactoryRepo() (*http.Response, error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/helm/" + path.Base(u.Path) + "/reindex"
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authenticate(req)
+	return c.httpClient.Do(req)
+}
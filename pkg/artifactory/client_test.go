@@ -0,0 +1,89 @@
+package artifactory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatrixParams(t *testing.T) {
+	if got := matrixParams(nil); got != "" {
+		t.Errorf("matrixParams(nil) = %q, want empty string", got)
+	}
+
+	got := matrixParams(map[string]string{"build.name": "ci", "git.commit": "abc 123"})
+	want := ";build.name=ci;git.commit=abc+123"
+	if got != want {
+		t.Errorf("matrixParams(...) = %q, want %q", got, want)
+	}
+}
+
+func TestUploadChartPackagePublishesBuildInfo(t *testing.T) {
+	var buildInfoReq buildInfo
+	var uploadPath string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repo/", func(w http.ResponseWriter, r *http.Request) {
+		uploadPath = r.URL.String()
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/api/build", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("reading build info body: %v", err)
+		}
+		if err := json.Unmarshal(body, &buildInfoReq); err != nil {
+			t.Fatalf("unmarshaling build info: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c, err := NewClient(
+		URL(server.URL+"/repo"),
+		Properties(map[string]string{"build.number": "42"}),
+		BuildInfo("my-build", "42", "https://ci.example.com/42"),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "artifactory-test-")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chartPackagePath := filepath.Join(dir, "mychart-0.1.0.tgz")
+	if err := ioutil.WriteFile(chartPackagePath, []byte("fake-tgz-bytes"), 0644); err != nil {
+		t.Fatalf("unable to write fake chart package: %v", err)
+	}
+
+	resp, err := c.UploadChartPackage("mychart", "0.1.0", chartPackagePath)
+	if err != nil {
+		t.Fatalf("UploadChartPackage: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("UploadChartPackage status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	wantPath := "/repo/mychart-0.1.0.tgz;build.number=42"
+	if uploadPath != wantPath {
+		t.Errorf("upload path = %q, want %q", uploadPath, wantPath)
+	}
+
+	if buildInfoReq.Name != "my-build" || buildInfoReq.Number != "42" {
+		t.Fatalf("build info = %+v, want name=my-build number=42", buildInfoReq)
+	}
+	if len(buildInfoReq.Modules) != 1 || buildInfoReq.Modules[0].ID != "mychart:0.1.0" {
+		t.Fatalf("build info modules = %+v, want a single mychart:0.1.0 module", buildInfoReq.Modules)
+	}
+	if len(buildInfoReq.Modules[0].Artifacts) != 1 || buildInfoReq.Modules[0].Artifacts[0].Sha256 == "" {
+		t.Fatalf("build info artifact missing sha256: %+v", buildInfoReq.Modules[0].Artifacts)
+	}
+}
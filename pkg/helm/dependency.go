@@ -0,0 +1,285 @@
+package helm
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/ghodss/yaml"
+	"helm-push-artifactory-plugin/pkg/artifactory"
+	helmrepo "helm-push-artifactory-plugin/pkg/repo"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/repo"
+	helmresolver "k8s.io/helm/pkg/resolver"
+)
+
+// DependencyResolverOptions configures how UpdateDependencies authenticates
+// against the repositories declared in a chart's dependencies, reusing the
+// same username/password/access-token/api-key/TLS settings pushCmd already
+// accepts for the Artifactory upload so private parent repos work the same
+// way on both paths.
+type DependencyResolverOptions struct {
+	Username           string
+	Password           string
+	AccessToken        string
+	ApiKey             string
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// UpdateDependencies resolves the chart directory's declared dependencies
+// (from Chart.yaml or requirements.yaml, whichever declares a
+// "dependencies" list) against their configured repositories, downloads
+// any .tgz not already present in charts/, and rewrites requirements.lock
+// with a fresh digest of requirements.yaml. It mirrors `helm dep update` /
+// `helm package --dependency-update`.
+func UpdateDependencies(chartDir string, opts DependencyResolverOptions) error {
+	reqs, err := loadRequirements(chartDir)
+	if err != nil {
+		return err
+	}
+	if len(reqs.Dependencies) == 0 {
+		return nil
+	}
+
+	tlsConfig, err := artifactory.NewTLSConfig(opts.CAFile, opts.CertFile, opts.KeyFile, opts.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	r := &resolver{
+		opts:   opts,
+		client: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+	}
+
+	chartsDir := filepath.Join(chartDir, "charts")
+	if err := os.MkdirAll(chartsDir, 0755); err != nil {
+		return fmt.Errorf("unable to create charts/ directory: %v", err)
+	}
+
+	locked := make([]*chartutil.Dependency, 0, len(reqs.Dependencies))
+	for _, dep := range reqs.Dependencies {
+		resolvedVersion, err := r.resolve(dep, chartsDir)
+		if err != nil {
+			return fmt.Errorf("unable to resolve dependency %q: %v", dep.Name, err)
+		}
+		locked = append(locked, &chartutil.Dependency{
+			Name:         dep.Name,
+			Version:      resolvedVersion,
+			Repository:   dep.Repository,
+			Condition:    dep.Condition,
+			Tags:         dep.Tags,
+			Enabled:      dep.Enabled,
+			ImportValues: dep.ImportValues,
+			Alias:        dep.Alias,
+		})
+	}
+
+	digest, err := helmresolver.HashReq(reqs)
+	if err != nil {
+		return err
+	}
+
+	return writeRequirementsLock(chartDir, &chartutil.RequirementsLock{
+		Generated:    time.Now(),
+		Digest:       digest,
+		Dependencies: locked,
+	})
+}
+
+// loadRequirements reads the dependencies list from whichever of
+// Chart.yaml or requirements.yaml declares one.
+func loadRequirements(chartDir string) (*chartutil.Requirements, error) {
+	for _, name := range []string{"requirements.yaml", "Chart.yaml"} {
+		raw, err := ioutil.ReadFile(filepath.Join(chartDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var reqs chartutil.Requirements
+		if err := yaml.Unmarshal(raw, &reqs); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %v", name, err)
+		}
+		if len(reqs.Dependencies) > 0 {
+			return &reqs, nil
+		}
+	}
+	return &chartutil.Requirements{}, nil
+}
+
+type resolver struct {
+	opts   DependencyResolverOptions
+	client *http.Client
+}
+
+// resolve downloads dep into chartsDir if it isn't already present and
+// returns the version that was actually fetched.
+func (r *resolver) resolve(dep *chartutil.Dependency, chartsDir string) (string, error) {
+	repoURL, err := r.repositoryURL(dep.Repository)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := r.fetchIndex(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	entry, err := chartEntry(index, dep.Name, dep.Version)
+	if err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(chartsDir, fmt.Sprintf("%s-%s.tgz", dep.Name, entry.Version))
+	if _, err := os.Stat(dest); err == nil {
+		return entry.Version, nil
+	}
+
+	if len(entry.URLs) == 0 {
+		return "", fmt.Errorf("no download URL published for %s-%s", dep.Name, entry.Version)
+	}
+	return entry.Version, r.download(resolveChartURL(repoURL, entry.URLs[0]), dest)
+}
+
+// repositoryURL turns a requirements.yaml "repository" value -- either a
+// plain URL or a local repo alias like "@myrepo" -- into the repository's
+// base URL.
+func (r *resolver) repositoryURL(repository string) (string, error) {
+	if !strings.HasPrefix(repository, "@") {
+		return strings.TrimSuffix(repository, "/"), nil
+	}
+
+	alias := strings.TrimPrefix(repository, "@")
+	repo, err := helmrepo.GetRepoByName(alias)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(repo.URL, "/"), nil
+}
+
+func (r *resolver) fetchIndex(repoURL string) (*repo.IndexFile, error) {
+	body, err := r.get(repoURL + "/index.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	raw, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &repo.IndexFile{}
+	if err := yaml.Unmarshal(raw, index); err != nil {
+		return nil, fmt.Errorf("unable to parse index.yaml: %v", err)
+	}
+	index.SortEntries()
+	return index, nil
+}
+
+func (r *resolver) download(u, dest string) error {
+	body, err := r.get(u)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, body)
+	return err
+}
+
+func (r *resolver) get(u string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case r.opts.AccessToken != "":
+		req.Header.Set("Authorization", "Bearer "+r.opts.AccessToken)
+	case r.opts.ApiKey != "":
+		req.Header.Set("X-JFrog-Art-Api", r.opts.ApiKey)
+	case r.opts.Username != "":
+		req.SetBasicAuth(r.opts.Username, r.opts.Password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected status %d", u, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// chartEntry finds the entry matching name in index whose version satisfies
+// constraint, a semver range exactly as requirements.yaml/Chart.yaml accept
+// (">=1.2.3 <2.0.0", "~1.4.0", a literal version, and so on), falling back
+// to the newest published version when constraint is empty or "*". Entries
+// are tried in index order, which fetchIndex sorts newest-first, so the
+// first match is the newest version satisfying the
+// constraint.
+func chartEntry(index *repo.IndexFile, name, constraint string) (*repo.ChartVersion, error) {
+	versions, ok := index.Entries[name]
+	if !ok || len(versions) == 0 {
+		return nil, fmt.Errorf("chart %q not found in repository index", name)
+	}
+
+	if constraint == "" || constraint == "*" {
+		return versions[0], nil
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("dependency %q has an invalid version/constraint format: %v", name, err)
+	}
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v.Version)
+		if err != nil {
+			continue
+		}
+		if c.Check(sv) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("no version matching %q found for chart %q", constraint, name)
+}
+
+// resolveChartURL resolves a (possibly relative) chart download URL
+// against the repository's base URL, as index.yaml entries are allowed to
+// publish either.
+func resolveChartURL(repoURL, chartURL string) string {
+	if strings.HasPrefix(chartURL, "http://") || strings.HasPrefix(chartURL, "https://") {
+		return chartURL
+	}
+	return repoURL + "/" + strings.TrimPrefix(chartURL, "/")
+}
+
+// writeRequirementsLock persists lock to requirements.lock, the file helm
+// v2's own dependency tooling (chartutil.LoadRequirementsLock, `helm dep
+// build`/`helm dep list`/`helm lint`) reads back and compares against
+// requirements.yaml's digest to verify charts/ is in sync.
+func writeRequirementsLock(chartDir string, lock *chartutil.RequirementsLock) error {
+	raw, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(chartDir, "requirements.lock"), raw, 0644)
+}
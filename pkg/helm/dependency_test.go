@@ -0,0 +1,64 @@
+package helm
+
+import (
+	"testing"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func TestChartEntry(t *testing.T) {
+	index := &repo.IndexFile{
+		Entries: map[string]repo.ChartVersions{
+			"mysubchart": {
+				{Metadata: &chart.Metadata{Version: "1.2.0"}},
+				{Metadata: &chart.Metadata{Version: "1.1.0"}},
+			},
+		},
+	}
+
+	entry, err := chartEntry(index, "mysubchart", "1.1.0")
+	if err != nil {
+		t.Fatalf("chartEntry: %v", err)
+	}
+	if entry.Version != "1.1.0" {
+		t.Errorf("Version = %q, want 1.1.0", entry.Version)
+	}
+
+	latest, err := chartEntry(index, "mysubchart", "")
+	if err != nil {
+		t.Fatalf("chartEntry (latest): %v", err)
+	}
+	if latest.Version != "1.2.0" {
+		t.Errorf("Version = %q, want 1.2.0 (first entry wins when unconstrained)", latest.Version)
+	}
+
+	ranged, err := chartEntry(index, "mysubchart", ">=1.0.0, <1.2.0")
+	if err != nil {
+		t.Fatalf("chartEntry (range): %v", err)
+	}
+	if ranged.Version != "1.1.0" {
+		t.Errorf("Version = %q, want 1.1.0 to satisfy >=1.0.0, <1.2.0", ranged.Version)
+	}
+
+	if _, err := chartEntry(index, "mysubchart", "9.9.9"); err == nil {
+		t.Error("expected an error for an unmatched version constraint")
+	}
+	if _, err := chartEntry(index, "missing", ""); err == nil {
+		t.Error("expected an error for a chart missing from the index")
+	}
+}
+
+func TestResolveChartURL(t *testing.T) {
+	cases := []struct {
+		repoURL, chartURL, want string
+	}{
+		{"https://artifactory/repo", "mysubchart-1.1.0.tgz", "https://artifactory/repo/mysubchart-1.1.0.tgz"},
+		{"https://artifactory/repo", "https://other/mysubchart-1.1.0.tgz", "https://other/mysubchart-1.1.0.tgz"},
+	}
+	for _, c := range cases {
+		if got := resolveChartURL(c.repoURL, c.chartURL); got != c.want {
+			t.Errorf("resolveChartURL(%q, %q) = %q, want %q", c.repoURL, c.chartURL, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiscoverCharts walks root and returns the path to every chart found
+// under it, either an unpacked chart directory (one containing a
+// Chart.yaml) or a packaged .tgz. A chart directory's own subtree is not
+// descended into further, so its charts/ dependencies aren't mistaken for
+// further top-level charts to push.
+func DiscoverCharts(root string) ([]string, error) {
+	var charts []string
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if _, statErr := os.Stat(filepath.Join(path, "Chart.yaml")); statErr == nil {
+				charts = append(charts, path)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".tgz") {
+			charts = append(charts, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(charts)
+	return charts, nil
+}
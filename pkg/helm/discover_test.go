@@ -0,0 +1,66 @@
+package helm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverCharts(t *testing.T) {
+	root, err := ioutil.TempDir("", "discover-charts-test-")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	mustMkdirAll(t, filepath.Join(root, "charta"))
+	mustWriteFile(t, filepath.Join(root, "charta", "Chart.yaml"), "name: charta\n")
+	mustMkdirAll(t, filepath.Join(root, "charta", "charts", "subchart"))
+	mustWriteFile(t, filepath.Join(root, "charta", "charts", "subchart", "Chart.yaml"), "name: subchart\n")
+
+	mustMkdirAll(t, filepath.Join(root, "nested", "chartb"))
+	mustWriteFile(t, filepath.Join(root, "nested", "chartb", "Chart.yaml"), "name: chartb\n")
+
+	mustWriteFile(t, filepath.Join(root, "chartc-0.1.0.tgz"), "fake-tgz-bytes")
+	mustWriteFile(t, filepath.Join(root, "README.md"), "not a chart")
+
+	charts, err := DiscoverCharts(root)
+	if err != nil {
+		t.Fatalf("DiscoverCharts: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(root, "chartc-0.1.0.tgz"),
+		filepath.Join(root, "charta"),
+		filepath.Join(root, "nested", "chartb"),
+	}
+	if len(charts) != len(want) {
+		t.Fatalf("DiscoverCharts = %v, want %v", charts, want)
+	}
+	for _, w := range want {
+		found := false
+		for _, c := range charts {
+			if c == w {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("DiscoverCharts missing %q, got %v", w, charts)
+		}
+	}
+}
+
+func mustMkdirAll(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("MkdirAll(%q): %v", path, err)
+	}
+}
+
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile(%q): %v", path, err)
+	}
+}
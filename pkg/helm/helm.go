@@ -0,0 +1,100 @@
+package helm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/strvals"
+)
+
+// Chart wraps a loaded Helm chart together with the path it was loaded
+// from, so callers can tell a chart directory apart from an already
+// packaged .tgz.
+type Chart struct {
+	*chart.Chart
+	path string
+}
+
+// GetChartByName loads a chart from either a packaged .tgz or a chart
+// directory on disk.
+func GetChartByName(name string) (*Chart, error) {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := chartutil.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load chart %q: %v", name, err)
+	}
+
+	return &Chart{Chart: c, path: path}, nil
+}
+
+// IsDir reports whether the chart was loaded from an unpacked directory
+// rather than a packaged .tgz.
+func (c *Chart) IsDir() bool {
+	fi, err := os.Stat(c.path)
+	return err == nil && fi.IsDir()
+}
+
+// Dir returns the directory the chart was loaded from. It only makes
+// sense to call this when IsDir() is true.
+func (c *Chart) Dir() string {
+	return c.path
+}
+
+// SetVersion overrides the chart version in Chart.yaml prior to packaging.
+func (c *Chart) SetVersion(version string) {
+	c.Metadata.Version = version
+}
+
+// SetAppVersion overrides the chart's app version in Chart.yaml prior to
+// packaging.
+func (c *Chart) SetAppVersion(appVersion string) {
+	c.Metadata.AppVersion = appVersion
+}
+
+// OverrideValues applies a list of "key=value" pairs on top of the
+// chart's values.yaml, using the same dotted-path syntax as `helm install
+// --set`.
+func (c *Chart) OverrideValues(overrides []string) error {
+	base := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(c.Values.Raw), &base); err != nil {
+		return fmt.Errorf("unable to parse values.yaml: %v", err)
+	}
+
+	for _, override := range overrides {
+		if err := strvals.ParseInto(override, base); err != nil {
+			return fmt.Errorf("unable to parse %q: %v", override, err)
+		}
+	}
+
+	raw, err := yaml.Marshal(base)
+	if err != nil {
+		return err
+	}
+	c.Values.Raw = string(raw)
+	return nil
+}
+
+// CreateChartPackage packages the chart into a .tgz under dest and
+// returns its path.
+func CreateChartPackage(c *Chart, dest string) (string, error) {
+	path, err := chartutil.Save(c.Chart, dest)
+	if err != nil {
+		return "", fmt.Errorf("unable to package chart: %v", err)
+	}
+	return path, nil
+}
+
+// NormalizeChartName strips any path separators, returning just the
+// chart's base name (e.g. for use in repository URLs).
+func NormalizeChartName(name string) string {
+	return strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+}
@@ -0,0 +1,109 @@
+package helm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// writeTestKeyring generates a throwaway PGP entity and serializes its
+// secret key ring to dir, returning the keyring path and the entity's
+// identity name (as accepted by --key).
+func writeTestKeyring(t *testing.T, dir string) (keyringPath, identity string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("Test Signer", "", "test-signer@example.com", nil)
+	if err != nil {
+		t.Fatalf("unable to generate test PGP entity: %v", err)
+	}
+
+	keyringPath = filepath.Join(dir, "secring.gpg")
+	f, err := os.Create(keyringPath)
+	if err != nil {
+		t.Fatalf("unable to create keyring file: %v", err)
+	}
+	defer f.Close()
+
+	if err := entity.SerializePrivate(f, nil); err != nil {
+		t.Fatalf("unable to serialize private key: %v", err)
+	}
+
+	for name := range entity.Identities {
+		identity = name
+	}
+	return keyringPath, identity
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helm-provenance-test-")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyringPath, identity := writeTestKeyring(t, dir)
+
+	chartPackagePath := filepath.Join(dir, "mychart-0.1.0.tgz")
+	if err := ioutil.WriteFile(chartPackagePath, []byte("fake-tgz-bytes"), 0644); err != nil {
+		t.Fatalf("unable to write fake chart package: %v", err)
+	}
+
+	c := &Chart{Chart: &chart.Chart{
+		Metadata: &chart.Metadata{Name: "mychart", Version: "0.1.0"},
+	}}
+
+	provPath, err := c.Sign(chartPackagePath, identity, keyringPath, "")
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	provBytes, err := ioutil.ReadFile(provPath)
+	if err != nil {
+		t.Fatalf("unable to read provenance file: %v", err)
+	}
+
+	block, _ := clearsign.Decode(provBytes)
+	if block == nil {
+		t.Fatalf("provenance file is not a valid clear-signed message")
+	}
+
+	keyring, err := openpgp.ReadKeyRing(mustOpen(t, keyringPath))
+	if err != nil {
+		t.Fatalf("unable to read keyring back: %v", err)
+	}
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+		t.Fatalf("signature did not verify: %v", err)
+	}
+
+	tarball, err := ioutil.ReadFile(chartPackagePath)
+	if err != nil {
+		t.Fatalf("unable to read chart package: %v", err)
+	}
+	sum := sha256.Sum256(tarball)
+	wantDigest := "sha256:" + hex.EncodeToString(sum[:])
+
+	if !strings.Contains(string(block.Plaintext), wantDigest) {
+		t.Errorf("provenance body missing digest %q:\n%s", wantDigest, block.Plaintext)
+	}
+	if !strings.Contains(string(block.Plaintext), "name: mychart") {
+		t.Errorf("provenance body missing chart metadata:\n%s", block.Plaintext)
+	}
+}
+
+func mustOpen(t *testing.T, path string) *os.File {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unable to open %q: %v", path, err)
+	}
+	return f
+}
@@ -0,0 +1,238 @@
+// Package oci implements just enough of the OCI Distribution Spec for
+// pushing a Helm chart (config + single content layer + manifest) to an
+// Artifactory OCI-backed Helm repository.
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client talks to an OCI Distribution Spec registry endpoint.
+type Client struct {
+	registry    string
+	username    string
+	password    string
+	accessToken string
+	apiKey      string
+	httpClient  *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client) error
+
+// NewClient builds an OCI distribution client for the given registry base
+// URL, scheme included (e.g. "https://artifactory.example.com").
+func NewClient(registry string, opts ...Option) (*Client, error) {
+	c := &Client{
+		registry:   strings.TrimSuffix(registry, "/"),
+		httpClient: &http.Client{},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Username sets the HTTP basic auth username used to obtain a bearer token.
+func Username(u string) Option {
+	return func(c *Client) error { c.username = u; return nil }
+}
+
+// Password sets the HTTP basic auth password used to obtain a bearer token.
+func Password(p string) Option {
+	return func(c *Client) error { c.password = p; return nil }
+}
+
+// AccessToken sets a pre-issued bearer token, bypassing the /v2/token exchange.
+func AccessToken(t string) Option {
+	return func(c *Client) error { c.accessToken = t; return nil }
+}
+
+// ApiKey sets the value of the X-JFrog-Art-Api header, used instead of
+// basic auth when present.
+func ApiKey(k string) Option {
+	return func(c *Client) error { c.apiKey = k; return nil }
+}
+
+// TLSConfig sets the TLS configuration used for registry connections,
+// shared with the caller's Artifactory client so --ca-file/--cert-file/
+// --insecure behave consistently across both code paths.
+func TLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *Client) error {
+		c.httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		return nil
+	}
+}
+
+// digest returns the "sha256:<hex>" digest of data, as used throughout the
+// distribution spec to address blobs and manifests.
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Login exchanges the client's configured credentials for a bearer token
+// scoped to push access on repoName by calling the registry's token endpoint
+// directly (GET /v2/token?service=...&scope=repository:<name>:push), with the
+// registry URL as the service. It does not read a WWW-Authenticate challenge.
+func (c *Client) Login(repoName string) error {
+	if c.accessToken != "" || c.apiKey != "" {
+		// Nothing to exchange: requests authenticate directly.
+		return nil
+	}
+	if c.username == "" {
+		return nil
+	}
+
+	u := fmt.Sprintf("%s/v2/token?service=%s&scope=repository:%s:push",
+		c.registry, url.QueryEscape(c.registry), url.QueryEscape(repoName))
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach registry token endpoint: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry login failed: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("unable to parse registry token response: %v", err)
+	}
+	c.accessToken = body.Token
+	return nil
+}
+
+func (c *Client) authenticate(req *http.Request) {
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("X-JFrog-Art-Api", c.apiKey)
+	case c.accessToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+}
+
+// PushBlob uploads a content blob to repoName, starting an upload session
+// and completing it in a single PUT with the computed digest, per the
+// distribution spec's monolithic upload flow. It returns the blob's
+// descriptor.
+func (c *Client) PushBlob(repoName string, data []byte, mediaType string) (Descriptor, error) {
+	d := digest(data)
+
+	startReq, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s/v2/%s/blobs/uploads/", c.registry, repoName), nil)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	c.authenticate(startReq)
+
+	startResp, err := c.httpClient.Do(startReq)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("unable to start blob upload: %v", err)
+	}
+	defer startResp.Body.Close()
+	if startResp.StatusCode != http.StatusAccepted {
+		return Descriptor{}, fmt.Errorf("unexpected status starting blob upload: %d", startResp.StatusCode)
+	}
+
+	location := startResp.Header.Get("Location")
+	if location == "" {
+		return Descriptor{}, fmt.Errorf("registry did not return an upload location")
+	}
+
+	putURL, err := appendDigestParam(c.registry, location, d)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	putReq, err := http.NewRequest(http.MethodPut, putURL, bytes.NewReader(data))
+	if err != nil {
+		return Descriptor{}, err
+	}
+	putReq.Header.Set("Content-Type", "application/octet-stream")
+	putReq.ContentLength = int64(len(data))
+	c.authenticate(putReq)
+
+	putResp, err := c.httpClient.Do(putReq)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("unable to upload blob: %v", err)
+	}
+	defer putResp.Body.Close()
+	if putResp.StatusCode != http.StatusCreated {
+		b, _ := ioutil.ReadAll(putResp.Body)
+		return Descriptor{}, fmt.Errorf("unexpected status uploading blob: %d: %s", putResp.StatusCode, string(b))
+	}
+
+	return Descriptor{MediaType: mediaType, Digest: d, Size: int64(len(data))}, nil
+}
+
+// PushManifest uploads the manifest for a chart version, completing the
+// OCI push.
+func (c *Client) PushManifest(repoName, version string, manifest Manifest) error {
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut,
+		fmt.Sprintf("%s/v2/%s/manifests/%s", c.registry, repoName, version), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ManifestMediaType)
+	c.authenticate(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to push manifest: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status pushing manifest: %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
+}
+
+// appendDigestParam resolves the (possibly relative) upload location
+// against the registry base URL and adds the digest query parameter,
+// preserving any query string the registry already attached.
+func appendDigestParam(registry, location, d string) (string, error) {
+	base, err := url.Parse(registry)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse registry URL: %v", err)
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse upload location: %v", err)
+	}
+	u := base.ResolveReference(ref)
+
+	q := u.Query()
+	q.Set("digest", d)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,83 @@
+package oci
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newMockRegistry serves just enough of the distribution spec for PushBlob
+// and PushManifest: it accepts any bearer/basic auth, always starts an
+// upload at /uploads/1, and records the blobs and manifests it receives.
+func newMockRegistry(t *testing.T) (*httptest.Server, map[string][]byte, map[string][]byte) {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/myrepo/blobs/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Location", "/v2/myrepo/blobs/uploads/1")
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPut:
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("reading blob body: %v", err)
+			}
+			blobs[r.URL.Query().Get("digest")] = body
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/v2/myrepo/manifests/0.1.0", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("reading manifest body: %v", err)
+		}
+		manifests["0.1.0"] = body
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	return httptest.NewServer(mux), blobs, manifests
+}
+
+func TestPushBlobAndManifest(t *testing.T) {
+	server, blobs, manifests := newMockRegistry(t)
+	defer server.Close()
+
+	c, err := NewClient(server.URL, Username("user"), Password("pass"))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	config := []byte(`{"name":"mychart","version":"0.1.0"}`)
+	configDesc, err := c.PushBlob("myrepo", config, ConfigMediaType)
+	if err != nil {
+		t.Fatalf("PushBlob(config): %v", err)
+	}
+	if configDesc.Digest != digest(config) {
+		t.Errorf("config digest = %q, want %q", configDesc.Digest, digest(config))
+	}
+
+	layer := []byte("fake-tgz-bytes")
+	layerDesc, err := c.PushBlob("myrepo", layer, ChartLayerMediaType)
+	if err != nil {
+		t.Fatalf("PushBlob(layer): %v", err)
+	}
+
+	if err := c.PushManifest("myrepo", "0.1.0", BuildManifest(configDesc, layerDesc)); err != nil {
+		t.Fatalf("PushManifest: %v", err)
+	}
+
+	if string(blobs[configDesc.Digest]) != string(config) {
+		t.Errorf("registry stored config blob = %q, want %q", blobs[configDesc.Digest], config)
+	}
+	if string(blobs[layerDesc.Digest]) != string(layer) {
+		t.Errorf("registry stored layer blob = %q, want %q", blobs[layerDesc.Digest], layer)
+	}
+	if _, ok := manifests["0.1.0"]; !ok {
+		t.Errorf("registry did not receive a manifest for version 0.1.0")
+	}
+}
@@ -0,0 +1,37 @@
+package oci
+
+const (
+	// ConfigMediaType is the media type of a Helm chart's OCI config blob.
+	ConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+	// ChartLayerMediaType is the media type of a Helm chart's OCI content layer.
+	ChartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// ManifestMediaType is the media type of the OCI image manifest itself.
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// Descriptor identifies a content-addressable blob within a manifest, per
+// the OCI Image Manifest spec.
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Manifest is the subset of the OCI Image Manifest spec Helm uses to
+// describe a chart: a single config blob (Chart.yaml as JSON) and a
+// single layer blob (the chart .tgz).
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// BuildManifest assembles the manifest referencing the chart's config and
+// content layer blobs.
+func BuildManifest(config, layer Descriptor) Manifest {
+	return Manifest{
+		SchemaVersion: 2,
+		Config:        config,
+		Layers:        []Descriptor{layer},
+	}
+}
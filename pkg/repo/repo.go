@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/helm/pkg/helm/helmpath"
+	"k8s.io/helm/pkg/repo"
+)
+
+// Repo holds the connection details for a named local Helm repository,
+// as stored in $HELM_HOME/repository/repositories.yaml.
+type Repo struct {
+	Name     string
+	URL      string
+	Username string
+	Password string
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// GetRepoByName looks up a repository by name in the local Helm repository
+// list and returns its connection details.
+func GetRepoByName(name string) (*Repo, error) {
+	home := helmpath.Home(os.Getenv("HELM_HOME"))
+
+	f, err := repo.LoadRepositoriesFile(home.RepositoryFile())
+	if err != nil {
+		return nil, fmt.Errorf("unable to load local repository list: %v", err)
+	}
+
+	entry, found := f.Get(name)
+	if !found {
+		return nil, fmt.Errorf("no repo named %q found, please add it first", name)
+	}
+
+	return &Repo{
+		Name:     entry.Name,
+		URL:      entry.URL,
+		Username: entry.Username,
+		Password: entry.Password,
+		CAFile:   entry.CAFile,
+		CertFile: entry.CertFile,
+		KeyFile:  entry.KeyFile,
+	}, nil
+}
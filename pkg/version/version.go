@@ -0,0 +1,9 @@
+package version
+
+// Version is set at build time via -ldflags.
+var Version = "canary"
+
+// GetVersion returns the plugin version string.
+func GetVersion() string {
+	return Version
+}